@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// update regenerates testdata/*.txt expected match columns from the actual
+// output of processLine + regexp, instead of asserting against them. Run
+// with `go test -run TestExhaustive -update` after changing processLine
+// semantics on purpose.
+var update = flag.Bool("update", false, "regenerate testdata/*.txt expected outputs instead of checking them")
+
+// exhaustiveCase is one testdata/*.txt file: a set of raw input strings and
+// a set of regex patterns, with the expected MatchString result of each
+// pattern against each string (after processLine normalization) recorded
+// in Expected[pattern index][string index].
+type exhaustiveCase struct {
+	Strings  []string
+	Patterns []string
+	Expected [][]bool
+}
+
+// parseExhaustiveFile reads the RE2 exhaustive-log-style stanza format:
+//
+//	strings
+//	"first input"
+//	"second input"
+//	regexps
+//	"first pattern"
+//	 T F
+//	"second pattern"
+//	 F T
+//
+// Strings and patterns are quoted with Go double-quote syntax so control
+// characters (\b, \r, \x1b, ...) can be expressed directly. Each pattern is
+// followed by one line of space-separated T/F tokens, one per string, in
+// the same order the strings were declared.
+func parseExhaustiveFile(path string) (*exhaustiveCase, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %v", path, err)
+	}
+	defer file.Close()
+
+	tc := &exhaustiveCase{}
+	section := ""
+	pendingPattern := ""
+	havePendingPattern := false
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		switch trimmed {
+		case "strings":
+			section = "strings"
+			continue
+		case "regexps":
+			section = "regexps"
+			continue
+		}
+
+		switch section {
+		case "strings":
+			s, err := strconv.Unquote(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("%s: bad quoted string %q: %v", path, trimmed, err)
+			}
+			tc.Strings = append(tc.Strings, s)
+
+		case "regexps":
+			if !havePendingPattern {
+				p, err := strconv.Unquote(trimmed)
+				if err != nil {
+					return nil, fmt.Errorf("%s: bad quoted pattern %q: %v", path, trimmed, err)
+				}
+				pendingPattern = p
+				havePendingPattern = true
+				continue
+			}
+
+			fields := strings.Fields(trimmed)
+			if len(fields) != len(tc.Strings) {
+				return nil, fmt.Errorf("%s: pattern %q has %d match columns, want %d", path, pendingPattern, len(fields), len(tc.Strings))
+			}
+			row := make([]bool, len(fields))
+			for i, f := range fields {
+				row[i] = f == "T"
+			}
+			tc.Patterns = append(tc.Patterns, pendingPattern)
+			tc.Expected = append(tc.Expected, row)
+			havePendingPattern = false
+
+		default:
+			return nil, fmt.Errorf("%s: line %q found before a \"strings\" or \"regexps\" header", path, trimmed)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	if havePendingPattern {
+		return nil, fmt.Errorf("%s: pattern %q is missing its match-column line", path, pendingPattern)
+	}
+
+	return tc, nil
+}
+
+// writeExhaustiveFile serializes tc back to path in the format
+// parseExhaustiveFile expects, recomputing Expected from actual
+// processLine/regexp behavior first.
+func writeExhaustiveFile(path string, tc *exhaustiveCase) error {
+	var sb strings.Builder
+
+	sb.WriteString("strings\n")
+	for _, s := range tc.Strings {
+		sb.WriteString(strconv.Quote(s))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("regexps\n")
+	for pi, pattern := range tc.Patterns {
+		sb.WriteString(strconv.Quote(pattern))
+		sb.WriteString("\n ")
+		cols := make([]string, len(tc.Strings))
+		for si := range tc.Strings {
+			if tc.Expected[pi][si] {
+				cols[si] = "T"
+			} else {
+				cols[si] = "F"
+			}
+		}
+		sb.WriteString(strings.Join(cols, " "))
+		sb.WriteString("\n")
+	}
+
+	return os.WriteFile(path, []byte(sb.String()), 0o644)
+}
+
+// TestExhaustive walks testdata/*.txt and, for every (pattern, string) pair
+// in each file, checks that pattern.MatchString(processLine(string))
+// matches the recorded expectation. This locks in processLine's control
+// character, backspace and cursor-movement semantics against a larger,
+// table-free corpus than hand-written cases allow.
+func TestExhaustive(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.txt")
+	if err != nil {
+		t.Fatalf("globbing testdata/*.txt: %v", err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no testdata/*.txt files found")
+	}
+
+	for _, path := range files {
+		path := path
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			tc, err := parseExhaustiveFile(path)
+			if err != nil {
+				t.Fatalf("parsing %s: %v", path, err)
+			}
+
+			compiled := make([]*regexp.Regexp, len(tc.Patterns))
+			for pi, pattern := range tc.Patterns {
+				regex, err := regexp.Compile(pattern)
+				if err != nil {
+					t.Fatalf("%s: pattern %q does not compile: %v", path, pattern, err)
+				}
+				compiled[pi] = regex
+			}
+
+			if *update {
+				for pi, regex := range compiled {
+					for si, s := range tc.Strings {
+						tc.Expected[pi][si] = regex.MatchString(processLine(s))
+					}
+				}
+				if err := writeExhaustiveFile(path, tc); err != nil {
+					t.Fatalf("updating %s: %v", path, err)
+				}
+				return
+			}
+
+			for pi, regex := range compiled {
+				for si, s := range tc.Strings {
+					got := regex.MatchString(processLine(s))
+					want := tc.Expected[pi][si]
+					if got != want {
+						t.Errorf("%s: pattern %q vs processLine(%q) = %q: got %t, want %t",
+							path, tc.Patterns[pi], s, processLine(s), got, want)
+					}
+				}
+			}
+		})
+	}
+}
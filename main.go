@@ -4,42 +4,65 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"regexp"
-	"strings"
-	"unicode"
+	"strconv"
+	"time"
 )
 
-type RegexSlice []*regexp.Regexp
-
-func (r *RegexSlice) String() string {
-	patterns := make([]string, len(*r))
-	for i, regex := range *r {
-		patterns[i] = regex.String()
-	}
-	return strings.Join(patterns, ",")
-}
-
-func (r *RegexSlice) Set(value string) error {
-	compiled, err := regexp.Compile(value)
-	if err != nil {
-		return fmt.Errorf("invalid regex pattern '%s': %v", value, err)
-	}
-	*r = append(*r, compiled)
-	return nil
-}
+// regexEngine selects the backend rule patterns compile with by default; it
+// is bound to the -regex-engine flag in main. Individual patterns can
+// override it with a "(?pcre)" prefix regardless of this value.
+var regexEngine = "re2"
 
 func main() {
 	// Command line flags for the regex patterns - support multiple patterns
-	var successPatterns RegexSlice
-	var failurePatterns RegexSlice
+	var successRules []*Rule
+	var failureRules []*Rule
+	var requireRules []*Rule
 	quiet := false
 	showHelp := false
 
-	flag.Var(&successPatterns, "success", "Regex pattern that causes exit with status 0 (can be specified multiple times)")
-	flag.Var(&failurePatterns, "failure", "Regex pattern that causes exit with status 1 (can be specified multiple times)")
+	var spawnCmd string
+	var scriptFile string
+	var script Script
+	var stepTimeout time.Duration
+
+	flag.StringVar(&regexEngine, "regex-engine", "re2", "Regex backend for -success/-failure/-require patterns: re2 (default, fast) or pcre (lookaround, backreferences, possessive quantifiers); a pattern prefixed with \"(?pcre)\" always uses pcre")
+	flag.Var(&ruleSlice{kind: RuleSuccess, rules: &successRules}, "success", "Regex pattern that causes exit with status 0, optionally with ,count=N ,within=DURATION ,after=DURATION modifiers (can be specified multiple times)")
+	flag.Var(&ruleSlice{kind: RuleFailure, rules: &failureRules}, "failure", "Regex pattern that causes exit with status 1, with the same modifiers as -success (can be specified multiple times)")
+	flag.Var(&ruleSlice{kind: RuleRequire, rules: &requireRules}, "require", "Regex pattern (optionally with ,count=N) that must also be satisfied before any -success can exit 0 (can be specified multiple times)")
 	flag.BoolVar(&quiet, "quiet", false, "Suppress output to stdout of the file contents")
 	flag.BoolVar(&showHelp, "help", false, "Show help message and exit")
+	flag.BoolVar(&keepColor, "keep-color", false, "Preserve SGR color escape codes in output instead of stripping them")
+
+	flag.StringVar(&spawnCmd, "spawn", "", "Launch \"cmd args...\" in a PTY and drive it through a scripted -expect/-send sequence instead of tailing stdin/a file")
+	flag.StringVar(&scriptFile, "script", "", "Load the -spawn interaction script from a file instead of repeated -expect/-send/-timeout flags")
+	flag.Func("expect", "Pattern the next step of a -spawn script must match before advancing (can be specified multiple times, in order)", func(value string) error {
+		compiled, err := regexp.Compile(value)
+		if err != nil {
+			return fmt.Errorf("invalid regex pattern '%s': %v", value, err)
+		}
+		script = append(script, Step{Kind: StepExpect, Pattern: compiled, Timeout: stepTimeout})
+		return nil
+	})
+	flag.Func("send", "Literal string to write to the -spawn child at this point in the script; a trailing \\n is written as a newline", func(value string) error {
+		unquoted, err := strconv.Unquote(`"` + value + `"`)
+		if err != nil {
+			unquoted = value
+		}
+		script = append(script, Step{Kind: StepSend, Payload: unquoted})
+		return nil
+	})
+	flag.Func("timeout", "Deadline applied to subsequent -expect steps until the next -timeout (used with -spawn)", func(value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid timeout '%s': %v", value, err)
+		}
+		stepTimeout = d
+		return nil
+	})
 
 	flag.Usage = printHelp
 
@@ -49,6 +72,18 @@ func main() {
 		printHelp()
 	}
 
+	if spawnCmd != "" {
+		if scriptFile != "" {
+			loaded, err := loadScriptFile(scriptFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(2)
+			}
+			script = loaded
+		}
+		os.Exit(runSpawn(spawnCmd, script, failureRules, quiet))
+	}
+
 	args := flag.Args()
 
 	input := os.Stdin
@@ -66,6 +101,16 @@ func main() {
 		input = file
 	}
 
+	os.Exit(runTail(input, successRules, failureRules, requireRules, quiet, time.Now()))
+}
+
+// runTail scans input line by line, applying the -require/-failure/-success
+// rules in that order on each line (a require that's still unmet blocks a
+// success from exiting even if the success pattern itself matched), and
+// returns the process exit code this line stream settles on. It's the body
+// of main's stdin/file tail mode, pulled out so the orchestration itself
+// (not just Rule.evaluate in isolation) can be driven directly from a test.
+func runTail(input io.Reader, successRules, failureRules, requireRules []*Rule, quiet bool, start time.Time) int {
 	scanner := bufio.NewScanner(input)
 
 	for scanner.Scan() {
@@ -76,50 +121,55 @@ func main() {
 			fmt.Println(processedLine)
 		}
 
-		for _, pattern := range successPatterns {
-			if pattern.MatchString(processedLine) {
-				os.Exit(0)
+		now := time.Now()
+
+		requireMet := true
+		for _, rule := range requireRules {
+			if !rule.evaluate(processedLine, now, start) {
+				requireMet = false
+			}
+		}
+
+		failureTripped := false
+		for _, rule := range failureRules {
+			if rule.evaluate(processedLine, now, start) {
+				failureTripped = true
 			}
 		}
+		if failureTripped {
+			return 1
+		}
 
-		for _, pattern := range failurePatterns {
-			if pattern.MatchString(processedLine) {
-				os.Exit(1)
+		successTripped := false
+		for _, rule := range successRules {
+			if rule.evaluate(processedLine, now, start) {
+				successTripped = true
 			}
 		}
+		if successTripped && requireMet {
+			return 0
+		}
 	}
 
 	if err := scanner.Err(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading from stdin: %v\n", err)
-		os.Exit(2)
+		return 2
 	}
 
 	fmt.Fprintf(os.Stderr, "EOF found but no matching lines were detected")
-	os.Exit(3)
+	return 3
 }
 
-// processLine deals with control characters in the line
+// processLine renders a raw line the way a terminal would display it:
+// backspace and '\r' move/erase rather than appearing literally, CSI/OSC
+// escape sequences are interpreted instead of leaking into the output, and
+// SGR color codes are stripped unless -keep-color is set.
 func processLine(line string) string {
-	var result []rune
-
+	renderer := NewLineRenderer()
 	for _, char := range line {
-		switch {
-		case char == '\b':
-			// Remove the backspace and the previous character (if any)
-			if len(result) > 0 {
-				result = result[:len(result)-1]
-			}
-
-		case unicode.IsControl(char):
-			// Skip all other control characters
-			continue
-
-		default:
-			result = append(result, char)
-		}
+		renderer.Feed(char)
 	}
-
-	return string(result)
+	return renderer.Line()
 }
 
 // printHelp displays usage and exit code information
@@ -132,12 +182,31 @@ func printHelp() {
 	fmt.Fprintf(os.Stderr, "Options:\n")
 	fmt.Fprintf(os.Stderr, "  -success <pattern>   Regex pattern that causes exit with status 0 (can be specified multiple times)\n")
 	fmt.Fprintf(os.Stderr, "  -failure <pattern>   Regex pattern that causes exit with status 1 (can be specified multiple times)\n")
+	fmt.Fprintf(os.Stderr, "  -require <pattern>   Regex pattern that must also be satisfied before any -success can exit 0\n")
+	fmt.Fprintf(os.Stderr, "                       (can be specified multiple times)\n")
+	fmt.Fprintf(os.Stderr, "\n")
+	fmt.Fprintf(os.Stderr, "                       -success/-failure/-require all accept comma-separated modifiers:\n")
+	fmt.Fprintf(os.Stderr, "                         count=N       require N matches, not just 1\n")
+	fmt.Fprintf(os.Stderr, "                         within=DUR    only count matches within a trailing window, e.g. 30s\n")
+	fmt.Fprintf(os.Stderr, "                         after=DUR     ignore matches before DUR has elapsed since start\n")
+	fmt.Fprintf(os.Stderr, "                       e.g. -success \"TEST PASSED,count=3,within=30s\"\n")
+	fmt.Fprintf(os.Stderr, "  -regex-engine <name> Backend for -success/-failure/-require: re2 (default) or pcre; a pattern\n")
+	fmt.Fprintf(os.Stderr, "                       may also start with \"(?pcre)\" to use pcre regardless of this flag\n")
 	fmt.Fprintf(os.Stderr, "  -quiet              Disables printing the processed lines to screen\n")
+	fmt.Fprintf(os.Stderr, "  -keep-color         Preserve SGR color escape codes in output instead of stripping them\n")
 	fmt.Fprintf(os.Stderr, "  -help               Show this help message and exit\n")
 	fmt.Fprintf(os.Stderr, "  arg                 When provided, the program will read from this file instead of stdin\n\n")
+	fmt.Fprintf(os.Stderr, "Scripted spawn mode (Linux only):\n")
+	fmt.Fprintf(os.Stderr, "  -spawn \"cmd args...\"  Launch cmd in a PTY and drive it through a scripted interaction\n")
+	fmt.Fprintf(os.Stderr, "  -script <file>         Load the script from a file instead of -expect/-send/-timeout\n")
+	fmt.Fprintf(os.Stderr, "  -expect <pattern>      Wait for the next line to match pattern before advancing\n")
+	fmt.Fprintf(os.Stderr, "  -send <string>         Write string to the child at this point in the script\n")
+	fmt.Fprintf(os.Stderr, "  -timeout <duration>    Deadline for subsequent -expect steps, e.g. 5s\n")
+	fmt.Fprintf(os.Stderr, "  -failure <pattern>     Also usable here: ends the script with exit 1 if any line matches,\n")
+	fmt.Fprintf(os.Stderr, "                         regardless of which -expect step is active\n\n")
 	fmt.Fprintf(os.Stderr, "Exit Codes:\n")
-	fmt.Fprintf(os.Stderr, "  0  Success pattern matched\n")
-	fmt.Fprintf(os.Stderr, "  1  Failure pattern matched\n")
+	fmt.Fprintf(os.Stderr, "  0  Success pattern's threshold was met and every -require pattern was too\n")
+	fmt.Fprintf(os.Stderr, "  1  Failure pattern's threshold was met\n")
 	fmt.Fprintf(os.Stderr, "  2  Error reading from stdin or opening file\n")
 	fmt.Fprintf(os.Stderr, "  3  EOF found but no matching lines were detected\n")
 	os.Exit(99)
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"os/exec"
 	"regexp"
 	"testing"
+	"time"
 )
 
 func TestProcessLine(t *testing.T) {
@@ -37,14 +39,14 @@ func TestProcessLine(t *testing.T) {
 			expected: "",
 		},
 		{
-			name:     "carriage return removed",
+			name:     "carriage return moves cursor to column 0 and overwrites",
 			input:    "hello\rworld",
-			expected: "helloworld",
+			expected: "world",
 		},
 		{
-			name:     "multiple carriage returns removed",
+			name:     "multiple carriage returns still just reset to column 0",
 			input:    "hello\r\r\rworld",
-			expected: "helloworld",
+			expected: "world",
 		},
 		{
 			name:     "tab character removed (control char)",
@@ -79,7 +81,7 @@ func TestProcessLine(t *testing.T) {
 		{
 			name:     "complex combination",
 			input:    "hello\b\rwo\trld\b\b\b\x1b test",
-			expected: "hellwo test",
+			expected: "wo test",
 		},
 		{
 			name:     "unicode characters preserved",
@@ -118,131 +120,6 @@ func TestProcessLine(t *testing.T) {
 	}
 }
 
-func TestRegexSliceString(t *testing.T) {
-	var rs RegexSlice
-
-	// Test empty slice
-	if rs.String() != "" {
-		t.Errorf("empty RegexSlice.String() = %q, want empty string", rs.String())
-	}
-
-	// Add some patterns
-	rs.Set("test")
-	rs.Set("hello.*world")
-
-	result := rs.String()
-	expected := "test,hello.*world"
-	if result != expected {
-		t.Errorf("RegexSlice.String() = %q, want %q", result, expected)
-	}
-}
-
-func TestRegexSliceSet(t *testing.T) {
-	tests := []struct {
-		name        string
-		pattern     string
-		shouldError bool
-	}{
-		{
-			name:        "valid simple pattern",
-			pattern:     "test",
-			shouldError: false,
-		},
-		{
-			name:        "valid regex pattern",
-			pattern:     "hello.*world",
-			shouldError: false,
-		},
-		{
-			name:        "valid complex pattern",
-			pattern:     "^(SUCCESS|COMPLETE).*\\d+$",
-			shouldError: false,
-		},
-		{
-			name:        "valid case-insensitive pattern",
-			pattern:     "(?i)error",
-			shouldError: false,
-		},
-		{
-			name:        "invalid pattern - unclosed bracket",
-			pattern:     "[abc",
-			shouldError: true,
-		},
-		{
-			name:        "invalid pattern - unclosed paren",
-			pattern:     "(abc",
-			shouldError: true,
-		},
-		{
-			name:        "invalid pattern - bad escape",
-			pattern:     "\\",
-			shouldError: true,
-		},
-		{
-			name:        "empty pattern is valid",
-			pattern:     "",
-			shouldError: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			var rs RegexSlice
-			err := rs.Set(tt.pattern)
-
-			if tt.shouldError && err == nil {
-				t.Errorf("RegexSlice.Set(%q) expected error but got none", tt.pattern)
-			}
-
-			if !tt.shouldError && err != nil {
-				t.Errorf("RegexSlice.Set(%q) unexpected error: %v", tt.pattern, err)
-			}
-
-			if !tt.shouldError && len(rs) != 1 {
-				t.Errorf("RegexSlice.Set(%q) expected 1 pattern, got %d", tt.pattern, len(rs))
-			}
-		})
-	}
-}
-
-func TestRegexSliceMultiplePatterns(t *testing.T) {
-	var rs RegexSlice
-
-	patterns := []string{"test", "hello.*world", "^SUCCESS"}
-
-	for _, pattern := range patterns {
-		err := rs.Set(pattern)
-		if err != nil {
-			t.Fatalf("unexpected error adding pattern %q: %v", pattern, err)
-		}
-	}
-
-	if len(rs) != len(patterns) {
-		t.Errorf("expected %d patterns, got %d", len(patterns), len(rs))
-	}
-
-	// Test that patterns work correctly
-	testCases := []struct {
-		input   string
-		matches []bool // which patterns should match
-	}{
-		{"test", []bool{true, false, false}},
-		{"hello beautiful world", []bool{false, true, false}},
-		{"SUCCESS: operation complete", []bool{false, false, true}},
-		{"no match here", []bool{false, false, false}},
-	}
-
-	for _, tc := range testCases {
-		for i, pattern := range rs {
-			matches := pattern.MatchString(tc.input)
-			if matches != tc.matches[i] {
-				t.Errorf("pattern %d (%q) matching %q: got %t, want %t",
-					i, pattern.String(), tc.input, matches, tc.matches[i])
-			}
-		}
-	}
-}
-
 func TestPatternMatching(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -332,12 +209,12 @@ func TestIntegrationProcessLineWithPatterns(t *testing.T) {
 			expectFailure:  false,
 		},
 		{
-			name:           "failure after removing control chars",
+			name:           "carriage return overwrite turns ERROR123 into 123OR",
 			input:          "ERROR\r\n\t123",
 			successPattern: "SUCCESS",
 			failurePattern: "ERROR\\d+",
 			expectSuccess:  false,
-			expectFailure:  true,
+			expectFailure:  false,
 		},
 		{
 			name:           "backspace reveals success",
@@ -348,11 +225,11 @@ func TestIntegrationProcessLineWithPatterns(t *testing.T) {
 			expectFailure:  false,
 		},
 		{
-			name:           "carriage return hiding failure",
+			name:           "carriage return overwrite leaves failure but not success",
 			input:          "SUCCESS\rFAILED",
 			successPattern: "SUCCESS",
 			failurePattern: "FAILED",
-			expectSuccess:  true,
+			expectSuccess:  false,
 			expectFailure:  true,
 		},
 		{
@@ -395,6 +272,80 @@ func TestIntegrationProcessLineWithPatterns(t *testing.T) {
 	}
 }
 
+// runTailOverCommand feeds runTail the real stdout of a freshly spawned
+// shell command, the same way it reads from a piped stdin in production,
+// so these exercise main's actual require/failure/success orchestration
+// rather than just Rule.evaluate or parseRuleSpec in isolation.
+func runTailOverCommand(t *testing.T, cmdLine string, successRules, failureRules, requireRules []*Rule) int {
+	t.Helper()
+	cmd := exec.Command("sh", "-c", cmdLine)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	code := runTail(stdout, successRules, failureRules, requireRules, true, time.Now())
+	cmd.Wait()
+	return code
+}
+
+func TestRunTailRequireGatesSuccessUntilBothMatch(t *testing.T) {
+	var successRules, requireRules []*Rule
+	if err := (&ruleSlice{kind: RuleSuccess, rules: &successRules}).Set("READY"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := (&ruleSlice{kind: RuleRequire, rules: &requireRules}).Set("LICENSED"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// READY arrives before the required LICENSED line does: the loop must
+	// not exit 0 on READY alone, only once LICENSED has also been seen.
+	code := runTailOverCommand(t, `printf 'READY\nLICENSED\n'`, successRules, nil, requireRules)
+	if code != 0 {
+		t.Errorf("runTail() = %d, want 0 once both READY and LICENSED have been seen", code)
+	}
+}
+
+func TestRunTailExitsWithoutSuccessWhenRequireNeverMet(t *testing.T) {
+	var successRules, requireRules []*Rule
+	if err := (&ruleSlice{kind: RuleSuccess, rules: &successRules}).Set("READY"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := (&ruleSlice{kind: RuleRequire, rules: &requireRules}).Set("LICENSED"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// READY matches on every line but LICENSED never shows up: the stream
+	// should run to EOF unsatisfied (exit 3), never exit 0 on READY alone.
+	code := runTailOverCommand(t, `printf 'READY\nREADY\n'`, successRules, nil, requireRules)
+	if code != 3 {
+		t.Errorf("runTail() = %d, want 3 (require never satisfied)", code)
+	}
+}
+
+func TestRunTailFailureExitsImmediatelyEvenIfRequireUnmet(t *testing.T) {
+	var successRules, failureRules, requireRules []*Rule
+	if err := (&ruleSlice{kind: RuleSuccess, rules: &successRules}).Set("READY"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := (&ruleSlice{kind: RuleFailure, rules: &failureRules}).Set("PANIC"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := (&ruleSlice{kind: RuleRequire, rules: &requireRules}).Set("LICENSED"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// PANIC shows up before LICENSED or READY ever do: a failure match must
+	// end the run right away, it doesn't wait on -require the way -success
+	// does.
+	code := runTailOverCommand(t, `printf 'PANIC\nREADY\n'`, successRules, failureRules, requireRules)
+	if code != 1 {
+		t.Errorf("runTail() = %d, want 1 (failure trips regardless of require state)", code)
+	}
+}
+
 // Benchmark tests for performance
 func BenchmarkProcessLine(b *testing.B) {
 	testLine := "hello\b\b\rworld\ttest\b\b\nmore text"
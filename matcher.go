@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dlclark/regexp2"
+)
+
+// pcrePrefix lets an individual pattern opt into the PCRE engine regardless
+// of the global -regex-engine flag, e.g. "-success '(?pcre)(?<=foo)bar'".
+const pcrePrefix = "(?pcre)"
+
+// Matcher is satisfied by any compiled pattern handytail can test a line
+// against, independent of which regex engine produced it.
+type Matcher interface {
+	MatchString(s string) bool
+	String() string
+}
+
+// re2Matcher is the default, fast RE2-based engine backed by the standard
+// library's regexp package.
+type re2Matcher struct {
+	regex *regexp.Regexp
+}
+
+func (m re2Matcher) MatchString(s string) bool { return m.regex.MatchString(s) }
+func (m re2Matcher) String() string            { return m.regex.String() }
+
+// pcreMatcher is the opt-in engine for patterns that need lookaround,
+// backreferences or possessive quantifiers, which RE2 refuses to compile.
+type pcreMatcher struct {
+	regex *regexp2.Regexp
+}
+
+func (m pcreMatcher) MatchString(s string) bool {
+	matched, err := m.regex.MatchString(s)
+	return err == nil && matched
+}
+func (m pcreMatcher) String() string { return m.regex.String() }
+
+// compileMatcher compiles value with the engine named by defaultEngine
+// ("re2" or "pcre"), unless value itself carries a "(?pcre)" prefix, in
+// which case that pattern is always compiled with the PCRE engine
+// regardless of defaultEngine.
+func compileMatcher(value string, defaultEngine string) (Matcher, error) {
+	engine := defaultEngine
+	if strings.HasPrefix(value, pcrePrefix) {
+		engine = "pcre"
+		value = strings.TrimPrefix(value, pcrePrefix)
+	}
+
+	switch engine {
+	case "", "re2":
+		compiled, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern '%s': %v", value, err)
+		}
+		return re2Matcher{regex: compiled}, nil
+
+	case "pcre":
+		compiled, err := regexp2.Compile(value, regexp2.None)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern '%s': %v", value, err)
+		}
+		return pcreMatcher{regex: compiled}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown -regex-engine '%s' (want re2 or pcre)", engine)
+	}
+}
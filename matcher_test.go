@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestCompileMatcherRE2Default(t *testing.T) {
+	m, err := compileMatcher("^SUCCESS", "re2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := m.(re2Matcher); !ok {
+		t.Fatalf("expected a re2Matcher, got %T", m)
+	}
+
+	if !m.MatchString("SUCCESS: done") {
+		t.Error("expected pattern to match")
+	}
+}
+
+func TestCompileMatcherRE2RejectsLookahead(t *testing.T) {
+	if _, err := compileMatcher("foo(?=bar)", "re2"); err == nil {
+		t.Error("expected RE2 to reject a lookahead pattern")
+	}
+}
+
+func TestCompileMatcherPCREEngine(t *testing.T) {
+	m, err := compileMatcher("foo(?=bar)", "pcre")
+	if err != nil {
+		t.Fatalf("unexpected error compiling pcre pattern: %v", err)
+	}
+
+	if _, ok := m.(pcreMatcher); !ok {
+		t.Fatalf("expected a pcreMatcher, got %T", m)
+	}
+
+	if !m.MatchString("foobar") {
+		t.Error("expected lookahead pattern to match 'foobar'")
+	}
+	if m.MatchString("foobaz") {
+		t.Error("expected lookahead pattern not to match 'foobaz'")
+	}
+}
+
+func TestCompileMatcherInlinePCREPrefixOverridesEngine(t *testing.T) {
+	m, err := compileMatcher("(?pcre)foo(?=bar)", "re2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := m.(pcreMatcher); !ok {
+		t.Fatalf("expected (?pcre) prefix to force a pcreMatcher, got %T", m)
+	}
+}
+
+func TestCompileMatcherUnknownEngine(t *testing.T) {
+	if _, err := compileMatcher("foo", "grep"); err == nil {
+		t.Error("expected an error for an unknown regex engine")
+	}
+}
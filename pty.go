@@ -0,0 +1,140 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// runSpawn launches cmdLine in a PTY and drives it through script, feeding
+// each processed line from the child against only the current step's
+// pattern (rather than every pattern, as the stdin/file modes do) and
+// writing send payloads back to the child as steps advance. failureRules are
+// the -failure patterns (the same ones the stdin/file modes use, count/
+// within/after modifiers included), checked against every line the child
+// produces regardless of which step is active; a rule being satisfied, like
+// a step timeout, ends the script with exit 1. It returns the process exit
+// code to use for os.Exit.
+func runSpawn(cmdLine string, script Script, failureRules []*Rule, quiet bool) int {
+	var steps []Step
+	failures := append([]*Rule(nil), failureRules...)
+	for _, step := range script {
+		if step.Kind == StepFailure {
+			failures = append(failures, &Rule{Kind: RuleFailure, NeedCount: 1, Regex: re2Matcher{regex: step.Pattern}})
+			continue
+		}
+		steps = append(steps, step)
+	}
+
+	if len(steps) == 0 {
+		fmt.Fprintf(os.Stderr, "spawn mode requires at least one -expect/-send step or -script file\n")
+		return 2
+	}
+
+	start := time.Now()
+	cmd := exec.Command("sh", "-c", cmdLine)
+
+	ptmx, err := startPty(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error spawning '%s': %v\n", cmdLine, err)
+		return 2
+	}
+	defer ptmx.Close()
+
+	lines := make(chan string)
+
+	go func() {
+		scanner := bufio.NewScanner(ptmx)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	stepIndex := 0
+	for stepIndex < len(steps) {
+		step := steps[stepIndex]
+
+		if step.Kind == StepSend {
+			payload := step.Payload
+			if _, err := ptmx.WriteString(payload); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing to child: %v\n", err)
+				cmd.Process.Kill()
+				return 2
+			}
+			stepIndex++
+			continue
+		}
+
+		ctx, cancel := newTimeoutContext(step.Timeout)
+
+		matched := false
+		for !matched {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					cancel()
+					cmd.Wait()
+					fmt.Fprintf(os.Stderr, "Child exited before step %d matched\n", stepIndex+1)
+					return 1
+				}
+
+				processedLine := processLine(line)
+				if !quiet {
+					fmt.Println(processedLine)
+				}
+
+				if rule, ok := firstTripped(failures, processedLine, time.Now(), start); ok {
+					cancel()
+					cmd.Process.Kill()
+					cmd.Wait()
+					fmt.Fprintf(os.Stderr, "Failure pattern matched: %s\n", rule.Regex.String())
+					return 1
+				}
+
+				if step.Pattern.MatchString(processedLine) {
+					matched = true
+				}
+
+			case <-ctx.Done():
+				cancel()
+				cmd.Process.Kill()
+				fmt.Fprintf(os.Stderr, "Timed out waiting for step %d (%s)\n", stepIndex+1, step.Pattern.String())
+				return 1
+			}
+		}
+
+		cancel()
+		stepIndex++
+	}
+
+	cmd.Process.Kill()
+	cmd.Wait()
+	return 0
+}
+
+// firstTripped returns the first of rules whose evaluate is satisfied by
+// line, honoring each rule's count/within/after modifiers the same way the
+// stdin/file modes do.
+func firstTripped(rules []*Rule, line string, now, start time.Time) (*Rule, bool) {
+	for _, r := range rules {
+		if r.evaluate(line, now, start) {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// newTimeoutContext is a small helper kept separate from the main select
+// loop so per-step deadlines are easy to unit test in isolation.
+func newTimeoutContext(d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), d)
+}
@@ -0,0 +1,83 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// Linux ioctl request numbers for pseudo-terminal setup. These are ABI
+// constants (see linux/tty.h); we hardcode them here rather than pulling in
+// golang.org/x/sys/unix for two integers.
+const (
+	ioctlTIOCGPTN   = 0x80045430 // get pty number
+	ioctlTIOCSPTLCK = 0x40045431 // (un)lock pty
+)
+
+func ioctl(fd uintptr, req uintptr, arg uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// openPty opens a new PTY master on /dev/ptmx and returns it along with the
+// path of its paired slave device.
+func openPty() (*os.File, string, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, "", fmt.Errorf("opening /dev/ptmx: %v", err)
+	}
+
+	var unlock int32
+	if err := ioctl(master.Fd(), ioctlTIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		master.Close()
+		return nil, "", fmt.Errorf("unlocking pty: %v", err)
+	}
+
+	var ptyNum int32
+	if err := ioctl(master.Fd(), ioctlTIOCGPTN, uintptr(unsafe.Pointer(&ptyNum))); err != nil {
+		master.Close()
+		return nil, "", fmt.Errorf("reading pty number: %v", err)
+	}
+
+	return master, "/dev/pts/" + strconv.Itoa(int(ptyNum)), nil
+}
+
+// startPty opens a new PTY, attaches it as cmd's controlling terminal on
+// stdin/stdout/stderr, and starts cmd. The returned file is the PTY master
+// the caller should read from and write to.
+func startPty(cmd *exec.Cmd) (*os.File, error) {
+	master, slaveName, err := openPty()
+	if err != nil {
+		return nil, err
+	}
+
+	slave, err := os.OpenFile(slaveName, os.O_RDWR, 0)
+	if err != nil {
+		master.Close()
+		return nil, fmt.Errorf("opening pty slave '%s': %v", slaveName, err)
+	}
+	defer slave.Close()
+
+	cmd.Stdin = slave
+	cmd.Stdout = slave
+	cmd.Stderr = slave
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setsid:  true,
+		Setctty: true,
+	}
+
+	if err := cmd.Start(); err != nil {
+		master.Close()
+		return nil, err
+	}
+
+	return master, nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// runSpawn is unavailable on this platform: -spawn relies on the Linux PTY
+// ioctls in pty_linux.go, which have no portable equivalent here. This stub
+// keeps the rest of handytail buildable on non-Linux targets and fails loudly
+// and explicitly at run time instead of leaving -spawn silently uncompiled.
+func runSpawn(cmdLine string, script Script, failureRules []*Rule, quiet bool) int {
+	fmt.Fprintf(os.Stderr, "-spawn is only supported on linux\n")
+	return 2
+}
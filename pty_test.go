@@ -0,0 +1,94 @@
+//go:build linux
+
+package main
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestFirstTripped(t *testing.T) {
+	rules := []*Rule{
+		{Kind: RuleFailure, NeedCount: 1, Regex: re2Matcher{regex: regexp.MustCompile("ERROR")}},
+		{Kind: RuleFailure, NeedCount: 2, Regex: re2Matcher{regex: regexp.MustCompile("TIMEOUT")}},
+	}
+	start := time.Unix(0, 0)
+
+	if _, ok := firstTripped(rules, "all good", start, start); ok {
+		t.Error("expected no rule tripped")
+	}
+
+	// The count=2 rule shouldn't trip on a single match.
+	if _, ok := firstTripped(rules, "request TIMEOUT after 5s", start, start); ok {
+		t.Error("expected count=2 rule not to trip on its first match")
+	}
+
+	rule, ok := firstTripped(rules, "request TIMEOUT after 5s", start, start)
+	if !ok {
+		t.Fatal("expected the count=2 rule to trip on its second match")
+	}
+	if rule.Regex.String() != "TIMEOUT" {
+		t.Errorf("tripped rule pattern = %q, want %q", rule.Regex.String(), "TIMEOUT")
+	}
+}
+
+func TestRunSpawnFailurePatternEndsScriptMidStep(t *testing.T) {
+	script := Script{
+		{Kind: StepExpect, Pattern: regexp.MustCompile("login:"), Timeout: 2 * time.Second},
+		// Never matched: the -failure hit below should end the script first.
+		{Kind: StepExpect, Pattern: regexp.MustCompile("Welcome"), Timeout: 2 * time.Second},
+	}
+	failureRules := []*Rule{
+		{Kind: RuleFailure, NeedCount: 1, Regex: re2Matcher{regex: regexp.MustCompile("ERROR")}},
+	}
+
+	code := runSpawn(`printf 'login:\nERROR: bad credentials\n'`, script, failureRules, true)
+	if code != 1 {
+		t.Errorf("runSpawn() = %d, want 1", code)
+	}
+}
+
+func TestRunSpawnFailureRuleHonorsCountModifier(t *testing.T) {
+	script := Script{
+		{Kind: StepExpect, Pattern: regexp.MustCompile("login:"), Timeout: 2 * time.Second},
+		{Kind: StepExpect, Pattern: regexp.MustCompile("Welcome"), Timeout: 2 * time.Second},
+	}
+	// Same "WARN,count=2" syntax the stdin/file modes accept: a single WARN
+	// line must not trip this, only a second one should. Each sub-case gets
+	// its own Rule, since a Rule accumulates match state across calls the
+	// same way it does across lines in one real run.
+	newFailureRules := func() []*Rule {
+		var rules []*Rule
+		rs := &ruleSlice{kind: RuleFailure, rules: &rules}
+		if err := rs.Set("WARN,count=2"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return rules
+	}
+
+	code := runSpawn(`printf 'login:\nWARN: retrying\nWelcome\n'`, script, newFailureRules(), true)
+	if code != 0 {
+		t.Errorf("runSpawn() = %d, want 0 (single WARN shouldn't trip count=2)", code)
+	}
+
+	code = runSpawn(`printf 'login:\nWARN: retrying\nWARN: retrying again\n'`, script, newFailureRules(), true)
+	if code != 1 {
+		t.Errorf("runSpawn() = %d, want 1 (second WARN should trip count=2)", code)
+	}
+}
+
+func TestRunSpawnSucceedsWithoutFailureMatch(t *testing.T) {
+	script := Script{
+		{Kind: StepExpect, Pattern: regexp.MustCompile("login:"), Timeout: 2 * time.Second},
+		{Kind: StepExpect, Pattern: regexp.MustCompile("Welcome"), Timeout: 2 * time.Second},
+	}
+	failureRules := []*Rule{
+		{Kind: RuleFailure, NeedCount: 1, Regex: re2Matcher{regex: regexp.MustCompile("ERROR")}},
+	}
+
+	code := runSpawn(`printf 'login:\nWelcome\n'`, script, failureRules, true)
+	if code != 0 {
+		t.Errorf("runSpawn() = %d, want 0", code)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RuleKind distinguishes what effect a satisfied Rule has on the main loop.
+type RuleKind int
+
+const (
+	RuleSuccess RuleKind = iota
+	RuleFailure
+	RuleRequire
+)
+
+// Rule tracks how many times its pattern has matched so -success/-failure
+// can require more than a single hit before exiting, optionally only
+// counting hits within a trailing time Window, and optionally ignoring
+// hits until After has elapsed since the program started.
+type Rule struct {
+	Regex     Matcher
+	NeedCount int
+	Window    time.Duration
+	After     time.Duration
+	matches   []time.Time
+	Kind      RuleKind
+}
+
+// evaluate records a match of line against the rule's pattern at time now
+// (ignoring matches before After has elapsed since start), drops matches
+// older than Window (a true sliding window: only the oldest matches age out,
+// not the whole count), and reports whether NeedCount matches remain within
+// the window.
+func (r *Rule) evaluate(line string, now, start time.Time) bool {
+	if r.Regex.MatchString(line) && now.Sub(start) >= r.After {
+		r.matches = append(r.matches, now)
+	}
+
+	if r.Window > 0 {
+		cutoff := now.Add(-r.Window)
+		i := 0
+		for i < len(r.matches) && r.matches[i].Before(cutoff) {
+			i++
+		}
+		r.matches = r.matches[i:]
+	} else if len(r.matches) > r.NeedCount {
+		// No window to age entries out of: once we've recorded enough
+		// matches to satisfy NeedCount, stop growing, since only the count
+		// matters from here on and the rule can otherwise live for the
+		// entire (possibly unbounded) life of the program.
+		r.matches = r.matches[len(r.matches)-r.NeedCount:]
+	}
+
+	return len(r.matches) >= r.NeedCount
+}
+
+// parseRuleSpec parses "PATTERN[,count=N][,within=DURATION][,after=DURATION]"
+// into a Rule of the given kind, compiling PATTERN with engine (see
+// compileMatcher). Modifiers are recognized left-to-right as
+// comma-separated "key=value" segments; anything that isn't a recognized
+// key=value pair is treated as part of the pattern, so patterns containing
+// literal commas still work as long as they don't happen to look like a
+// modifier.
+func parseRuleSpec(spec string, kind RuleKind, engine string) (*Rule, error) {
+	parts := strings.Split(spec, ",")
+	patternParts := parts[:1:1]
+	rule := &Rule{Kind: kind, NeedCount: 1}
+
+	for _, part := range parts[1:] {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			patternParts = append(patternParts, part)
+			continue
+		}
+
+		switch key {
+		case "count":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid count '%s' in '%s': must be a positive integer", value, spec)
+			}
+			rule.NeedCount = n
+
+		case "within":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid within '%s' in '%s': %v", value, spec, err)
+			}
+			rule.Window = d
+
+		case "after":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid after '%s' in '%s': %v", value, spec, err)
+			}
+			rule.After = d
+
+		default:
+			patternParts = append(patternParts, part)
+		}
+	}
+
+	matcher, err := compileMatcher(strings.Join(patternParts, ","), engine)
+	if err != nil {
+		return nil, err
+	}
+	rule.Regex = matcher
+
+	return rule, nil
+}
+
+// ruleSlice is a flag.Value that parses repeated -success/-failure/-require
+// flags into Rules of a fixed Kind, compiled with the regex engine in
+// effect when each flag is processed.
+type ruleSlice struct {
+	kind  RuleKind
+	rules *[]*Rule
+}
+
+func (s *ruleSlice) String() string {
+	if s.rules == nil {
+		return ""
+	}
+	patterns := make([]string, len(*s.rules))
+	for i, rule := range *s.rules {
+		patterns[i] = rule.Regex.String()
+	}
+	return strings.Join(patterns, ",")
+}
+
+func (s *ruleSlice) Set(value string) error {
+	rule, err := parseRuleSpec(value, s.kind, regexEngine)
+	if err != nil {
+		return err
+	}
+	*s.rules = append(*s.rules, rule)
+	return nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRuleSpecPlainPattern(t *testing.T) {
+	rule, err := parseRuleSpec("hello.*world", RuleSuccess, "re2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rule.NeedCount != 1 {
+		t.Errorf("NeedCount = %d, want 1", rule.NeedCount)
+	}
+	if rule.Window != 0 || rule.After != 0 {
+		t.Errorf("expected no window/after, got %v / %v", rule.Window, rule.After)
+	}
+	if !rule.Regex.MatchString("hello beautiful world") {
+		t.Error("expected pattern to match")
+	}
+}
+
+func TestParseRuleSpecModifiers(t *testing.T) {
+	rule, err := parseRuleSpec("TEST PASSED,count=3,within=30s,after=5s", RuleSuccess, "re2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rule.NeedCount != 3 {
+		t.Errorf("NeedCount = %d, want 3", rule.NeedCount)
+	}
+	if rule.Window != 30*time.Second {
+		t.Errorf("Window = %v, want 30s", rule.Window)
+	}
+	if rule.After != 5*time.Second {
+		t.Errorf("After = %v, want 5s", rule.After)
+	}
+	if !rule.Regex.MatchString("TEST PASSED") {
+		t.Error("expected pattern to match")
+	}
+}
+
+func TestParseRuleSpecInvalidModifier(t *testing.T) {
+	tests := []string{
+		"foo,count=0",
+		"foo,count=abc",
+		"foo,within=notaduration",
+		"foo,after=notaduration",
+	}
+	for _, spec := range tests {
+		if _, err := parseRuleSpec(spec, RuleFailure, "re2"); err == nil {
+			t.Errorf("parseRuleSpec(%q) expected an error, got none", spec)
+		}
+	}
+}
+
+func TestParseRuleSpecInvalidRegex(t *testing.T) {
+	if _, err := parseRuleSpec("[abc,count=2", RuleSuccess, "re2"); err == nil {
+		t.Error("expected an error for an invalid regex pattern")
+	}
+}
+
+func TestRuleEvaluateSingleMatch(t *testing.T) {
+	rule, err := parseRuleSpec("SUCCESS", RuleSuccess, "re2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Unix(0, 0)
+	now := start
+
+	if rule.evaluate("nope", now, start) {
+		t.Error("expected no match to leave the rule unsatisfied")
+	}
+	if !rule.evaluate("SUCCESS", now, start) {
+		t.Error("expected a single match to satisfy a count=1 rule")
+	}
+}
+
+func TestRuleEvaluateCount(t *testing.T) {
+	rule, err := parseRuleSpec("PASS,count=3", RuleSuccess, "re2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Unix(0, 0)
+	now := start
+
+	for i := 0; i < 2; i++ {
+		if rule.evaluate("PASS", now, start) {
+			t.Errorf("rule satisfied too early after %d matches", i+1)
+		}
+	}
+	if !rule.evaluate("PASS", now, start) {
+		t.Error("expected the third match to satisfy count=3")
+	}
+}
+
+func TestRuleEvaluateWithinWindowDropsOnlyStaleMatches(t *testing.T) {
+	rule, err := parseRuleSpec("PASS,count=2,within=10s", RuleSuccess, "re2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Unix(0, 0)
+
+	if rule.evaluate("PASS", start, start) {
+		t.Error("rule satisfied after only one match")
+	}
+
+	// Second match arrives after the window has elapsed relative to the
+	// first: only that stale match ages out, it doesn't throw away the
+	// whole count, so this match alone still isn't enough.
+	late := start.Add(20 * time.Second)
+	if rule.evaluate("PASS", late, start) {
+		t.Error("rule should not be satisfied once the first match has aged out")
+	}
+
+	// A further match soon after does satisfy count=2 within the window.
+	if !rule.evaluate("PASS", late.Add(time.Second), start) {
+		t.Error("expected a second match within the window to satisfy the rule")
+	}
+}
+
+func TestRuleEvaluateWithinWindowIsSliding(t *testing.T) {
+	// count=3,within=10s with hits at t=0,9,15,16: a tumbling/reset window
+	// would throw away the whole count when t=0 ages out at t=15, and never
+	// fire. A true sliding window only drops t=0 and is satisfied at t=16
+	// by t=9, t=15 and t=16.
+	rule, err := parseRuleSpec("PASS,count=3,within=10s", RuleSuccess, "re2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Unix(0, 0)
+
+	if rule.evaluate("PASS", start, start) {
+		t.Error("rule satisfied after only one match")
+	}
+	if rule.evaluate("PASS", start.Add(9*time.Second), start) {
+		t.Error("rule satisfied after only two matches")
+	}
+	if rule.evaluate("PASS", start.Add(15*time.Second), start) {
+		t.Error("rule should not be satisfied yet: only t=9 and t=15 are within the window")
+	}
+	if !rule.evaluate("PASS", start.Add(16*time.Second), start) {
+		t.Error("expected t=9, t=15 and t=16 (all within 10s of t=16) to satisfy count=3")
+	}
+}
+
+func TestRuleEvaluateCountWithoutWindowDoesNotGrowUnbounded(t *testing.T) {
+	rule, err := parseRuleSpec("PASS,count=2", RuleSuccess, "re2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Unix(0, 0)
+	now := start
+	for i := 0; i < 1000; i++ {
+		now = now.Add(time.Second)
+		satisfied := rule.evaluate("PASS", now, start)
+		if i >= 1 && !satisfied {
+			t.Fatalf("expected rule to stay satisfied after match %d", i+1)
+		}
+	}
+
+	if got := len(rule.matches); got > rule.NeedCount {
+		t.Errorf("recorded %d matches with no within=, want at most NeedCount=%d", got, rule.NeedCount)
+	}
+}
+
+func TestRuleEvaluateAfterDelay(t *testing.T) {
+	rule, err := parseRuleSpec("PASS,after=10s", RuleSuccess, "re2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Unix(0, 0)
+
+	if rule.evaluate("PASS", start.Add(5*time.Second), start) {
+		t.Error("match before the after delay should not count")
+	}
+	if !rule.evaluate("PASS", start.Add(11*time.Second), start) {
+		t.Error("match after the after delay should count")
+	}
+}
+
+func TestRuleSliceFlagValue(t *testing.T) {
+	var rules []*Rule
+	rs := &ruleSlice{kind: RuleFailure, rules: &rules}
+
+	if err := rs.Set("ERROR"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := rs.Set("TIMEOUT,count=2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(rules))
+	}
+	if rules[0].Kind != RuleFailure || rules[1].Kind != RuleFailure {
+		t.Error("expected both rules to carry the RuleFailure kind")
+	}
+
+	want := "ERROR,TIMEOUT"
+	if got := rs.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
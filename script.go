@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StepKind identifies whether a Script step waits for output or sends input.
+type StepKind int
+
+const (
+	StepExpect StepKind = iota
+	StepSend
+	StepFailure
+)
+
+// Step is a single entry in a scripted interaction: wait for Pattern to
+// match a line from the child (StepExpect), write Payload to it (StepSend),
+// or, for StepFailure, name a pattern that ends the script with exit 1 if
+// any line matches it, regardless of which step is currently active.
+// Timeout is the per-step deadline for StepExpect steps; a zero value means
+// no deadline.
+type Step struct {
+	Kind    StepKind
+	Pattern *regexp.Regexp
+	Payload string
+	Timeout time.Duration
+}
+
+// Script is an ordered sequence of Steps. StepExpect/StepSend steps must all
+// be satisfied, in order, before -spawn mode exits 0; StepFailure steps are
+// unordered and are checked against every line throughout the script.
+type Script []Step
+
+// loadScriptFile reads a script from a simple stanza format: steps are
+// separated by blank lines, and each step is a series of "key: value"
+// lines. Recognized keys are "expect", "send", "failure" and "timeout".
+// This keeps -script dependency-free rather than pulling in a full YAML
+// parser for a handful of fields.
+func loadScriptFile(path string) (Script, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening script '%s': %v", path, err)
+	}
+	defer file.Close()
+
+	var script Script
+	var defaultTimeout time.Duration
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed script line %q (expected key: value)", line)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(strings.Trim(strings.TrimSpace(value), `"`))
+
+		switch key {
+		case "timeout":
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timeout %q: %v", value, err)
+			}
+			defaultTimeout = d
+
+		case "expect":
+			compiled, err := regexp.Compile(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex pattern '%s': %v", value, err)
+			}
+			script = append(script, Step{Kind: StepExpect, Pattern: compiled, Timeout: defaultTimeout})
+
+		case "failure":
+			compiled, err := regexp.Compile(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex pattern '%s': %v", value, err)
+			}
+			script = append(script, Step{Kind: StepFailure, Pattern: compiled})
+
+		case "send":
+			unquoted, err := strconv.Unquote(`"` + value + `"`)
+			if err != nil {
+				unquoted = value
+			}
+			script = append(script, Step{Kind: StepSend, Payload: unquoted})
+
+		default:
+			return nil, fmt.Errorf("unknown script key %q", key)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading script '%s': %v", path, err)
+	}
+
+	return script, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadScriptFile(t *testing.T) {
+	contents := `# login script
+timeout: 2s
+expect: "login:"
+send: "admin\n"
+expect: "password:"
+send: "hunter2\n"
+expect: "Welcome"
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.txt")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	script, err := loadScriptFile(path)
+	if err != nil {
+		t.Fatalf("loadScriptFile returned error: %v", err)
+	}
+
+	if len(script) != 5 {
+		t.Fatalf("expected 5 steps, got %d", len(script))
+	}
+
+	wantKinds := []StepKind{StepExpect, StepSend, StepExpect, StepSend, StepExpect}
+	for i, step := range script {
+		if step.Kind != wantKinds[i] {
+			t.Errorf("step %d: kind = %v, want %v", i, step.Kind, wantKinds[i])
+		}
+	}
+
+	if script[0].Timeout != 2*time.Second {
+		t.Errorf("step 0 timeout = %v, want 2s", script[0].Timeout)
+	}
+
+	if script[1].Payload != "admin\n" {
+		t.Errorf("step 1 payload = %q, want %q", script[1].Payload, "admin\n")
+	}
+
+	if !script[0].Pattern.MatchString("please login: ") {
+		t.Errorf("step 0 pattern did not match expected text")
+	}
+}
+
+func TestLoadScriptFileFailureStep(t *testing.T) {
+	contents := `expect: "login:"
+failure: "ERROR"
+send: "admin\n"
+expect: "Welcome"
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.txt")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	script, err := loadScriptFile(path)
+	if err != nil {
+		t.Fatalf("loadScriptFile returned error: %v", err)
+	}
+
+	wantKinds := []StepKind{StepExpect, StepFailure, StepSend, StepExpect}
+	if len(script) != len(wantKinds) {
+		t.Fatalf("expected %d steps, got %d", len(wantKinds), len(script))
+	}
+	for i, step := range script {
+		if step.Kind != wantKinds[i] {
+			t.Errorf("step %d: kind = %v, want %v", i, step.Kind, wantKinds[i])
+		}
+	}
+
+	if !script[1].Pattern.MatchString("ERROR: connection refused") {
+		t.Error("failure step pattern did not match expected text")
+	}
+}
+
+func TestLoadScriptFileInvalidRegex(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.txt")
+	if err := os.WriteFile(path, []byte("expect: [unterminated\n"), 0o644); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	if _, err := loadScriptFile(path); err == nil {
+		t.Error("expected an error for an invalid regex, got nil")
+	}
+}
+
+func TestLoadScriptFileMissingFile(t *testing.T) {
+	if _, err := loadScriptFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("expected an error for a missing script file, got nil")
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// keepColor controls whether LineRenderer preserves SGR (color) escape
+// sequences in its output instead of stripping them; it is bound to the
+// -keep-color flag in main.
+var keepColor = false
+
+type vtState int
+
+const (
+	vtNormal vtState = iota
+	vtEsc
+	vtCSI
+	vtOSC
+	vtOSCEsc
+)
+
+// cell is one visible character position in a LineRenderer's buffer, along
+// with any SGR escape sequence that should be emitted immediately before it
+// when colors are kept.
+type cell struct {
+	ch     rune
+	prefix string
+}
+
+// LineRenderer is a small VT100 state machine that turns a stream of runes
+// (as produced by a terminal-oriented process) into the single line of text
+// a viewer would actually see, the same way a terminal emulator applies
+// cursor movement and erase sequences rather than just concatenating bytes.
+// It understands CSI sequences (cursor movement, line erase, SGR color),
+// OSC sequences (e.g. window title, skipped entirely), and treats '\r' and
+// backspace as cursor movement rather than literal characters.
+type LineRenderer struct {
+	buf           []cell
+	col           int
+	state         vtState
+	params        []rune
+	pendingPrefix string
+}
+
+// NewLineRenderer returns a LineRenderer ready to Feed runes into.
+func NewLineRenderer() *LineRenderer {
+	return &LineRenderer{}
+}
+
+// Feed processes a single rune, updating the renderer's internal cursor and
+// buffer as a terminal would.
+func (r *LineRenderer) Feed(ch rune) {
+	switch r.state {
+	case vtEsc:
+		r.feedEsc(ch)
+	case vtCSI:
+		r.feedCSI(ch)
+	case vtOSC:
+		r.feedOSC(ch)
+	case vtOSCEsc:
+		r.feedOSCEsc(ch)
+	default:
+		r.feedNormal(ch)
+	}
+}
+
+func (r *LineRenderer) feedNormal(ch rune) {
+	switch {
+	case ch == '\x1b':
+		r.state = vtEsc
+
+	case ch == '\r':
+		r.col = 0
+
+	case ch == '\b':
+		r.backspace()
+
+	case unicode.IsControl(ch):
+		// Skip all other control characters, same as plain text mode.
+
+	default:
+		r.write(ch)
+	}
+}
+
+func (r *LineRenderer) feedEsc(ch rune) {
+	switch ch {
+	case '[':
+		r.state = vtCSI
+		r.params = r.params[:0]
+		return
+
+	case ']':
+		r.state = vtOSC
+		r.params = r.params[:0]
+		return
+	}
+
+	// SS2 ('N'), SS3 ('O') and anything else we don't model: drop the
+	// introducer and feed ch through as ordinary text.
+	r.state = vtNormal
+	r.feedNormal(ch)
+}
+
+func (r *LineRenderer) feedCSI(ch rune) {
+	// Final bytes are in the range 0x40-0x7E; everything before that
+	// (parameter and intermediate bytes) accumulates.
+	if ch >= '@' && ch <= '~' {
+		r.applyCSI(ch, string(r.params))
+		r.state = vtNormal
+		return
+	}
+	r.params = append(r.params, ch)
+}
+
+func (r *LineRenderer) feedOSC(ch rune) {
+	switch ch {
+	case '\a':
+		r.state = vtNormal
+	case '\x1b':
+		r.state = vtOSCEsc
+	default:
+		r.params = append(r.params, ch)
+	}
+}
+
+func (r *LineRenderer) feedOSCEsc(ch rune) {
+	if ch == '\\' {
+		// ESC \ (String Terminator): OSC sequence is complete and discarded.
+		r.state = vtNormal
+		return
+	}
+	// Not actually a terminator; the ESC belongs to the OSC payload.
+	r.params = append(r.params, '\x1b', ch)
+	r.state = vtOSC
+}
+
+// applyCSI interprets a completed "ESC [ params final" sequence.
+func (r *LineRenderer) applyCSI(final rune, params string) {
+	switch final {
+	case 'D': // Cursor left n
+		r.col -= csiInt(params, 1)
+		if r.col < 0 {
+			r.col = 0
+		}
+
+	case 'C': // Cursor right n
+		r.col += csiInt(params, 1)
+		for len(r.buf) < r.col {
+			r.buf = append(r.buf, cell{ch: ' '})
+		}
+
+	case 'K': // Erase in line
+		switch csiInt(params, 0) {
+		case 0: // cursor to end of line
+			if r.col < len(r.buf) {
+				r.buf = r.buf[:r.col]
+			}
+		case 1: // start of line to cursor
+			for i := 0; i < r.col && i < len(r.buf); i++ {
+				r.buf[i].ch = ' '
+			}
+		case 2: // entire line
+			r.buf = r.buf[:0]
+			r.col = 0
+		}
+
+	case 'm': // SGR (color/attribute) codes
+		if keepColor {
+			r.pendingPrefix += "\x1b[" + params + "m"
+		}
+
+	default:
+		// Unsupported sequence: ignore, matching the old behavior of
+		// dropping anything it didn't understand.
+	}
+}
+
+func (r *LineRenderer) write(ch rune) {
+	c := cell{ch: ch, prefix: r.pendingPrefix}
+	r.pendingPrefix = ""
+
+	// Assign the cell outright rather than merging into whatever was there
+	// before: on an overwrite (e.g. a '\r'-redrawn colored progress bar),
+	// the old prefix belongs to a character this write is replacing, not
+	// one it's layering on top of. Appending here would grow every
+	// repeated overwrite at this column without bound.
+	if r.col < len(r.buf) {
+		r.buf[r.col] = c
+	} else {
+		r.buf = append(r.buf, c)
+	}
+	r.col++
+}
+
+// backspace deletes the character immediately before the cursor, shifting
+// the rest of the line left. This mirrors handytail's historical behavior
+// rather than a real terminal's (where backspace only moves the cursor).
+func (r *LineRenderer) backspace() {
+	if r.col == 0 {
+		return
+	}
+	r.buf = append(r.buf[:r.col-1], r.buf[r.col:]...)
+	r.col--
+}
+
+// Line returns the text the terminal would currently be displaying.
+func (r *LineRenderer) Line() string {
+	var sb strings.Builder
+	for _, c := range r.buf {
+		sb.WriteString(c.prefix)
+		sb.WriteRune(c.ch)
+	}
+	sb.WriteString(r.pendingPrefix)
+	return sb.String()
+}
+
+// Reset clears the renderer so it can be reused for the next line.
+func (r *LineRenderer) Reset() {
+	r.buf = r.buf[:0]
+	r.col = 0
+	r.state = vtNormal
+	r.params = r.params[:0]
+	r.pendingPrefix = ""
+}
+
+// csiInt parses a CSI parameter string (digits only; handytail doesn't need
+// the ';'-separated multi-parameter forms) and returns def if it's empty.
+func csiInt(params string, def int) int {
+	if params == "" {
+		return def
+	}
+	n, err := strconv.Atoi(params)
+	if err != nil {
+		return def
+	}
+	return n
+}
@@ -0,0 +1,113 @@
+package main
+
+import "testing"
+
+func feedString(r *LineRenderer, s string) {
+	for _, ch := range s {
+		r.Feed(ch)
+	}
+}
+
+func TestLineRendererProgressBarOverwrite(t *testing.T) {
+	r := NewLineRenderer()
+	feedString(r, "Downloading: 10%\rDownloading: 99%")
+	if got, want := r.Line(), "Downloading: 99%"; got != want {
+		t.Errorf("Line() = %q, want %q", got, want)
+	}
+}
+
+func TestLineRendererCursorLeftOverwrite(t *testing.T) {
+	r := NewLineRenderer()
+	feedString(r, "hello\x1b[3Dxyz")
+	if got, want := r.Line(), "hexyz"; got != want {
+		t.Errorf("Line() = %q, want %q", got, want)
+	}
+}
+
+func TestLineRendererEraseToEndOfLine(t *testing.T) {
+	r := NewLineRenderer()
+	feedString(r, "hello world\r\x1b[Khi")
+	if got, want := r.Line(), "hi"; got != want {
+		t.Errorf("Line() = %q, want %q", got, want)
+	}
+}
+
+func TestLineRendererClearEntireLine(t *testing.T) {
+	r := NewLineRenderer()
+	feedString(r, "hello\x1b[2Kworld")
+	if got, want := r.Line(), "world"; got != want {
+		t.Errorf("Line() = %q, want %q", got, want)
+	}
+}
+
+func TestLineRendererOSCSequenceIgnored(t *testing.T) {
+	r := NewLineRenderer()
+	feedString(r, "before\x1b]0;window title\x07afterward")
+	if got, want := r.Line(), "beforeafterward"; got != want {
+		t.Errorf("Line() = %q, want %q", got, want)
+	}
+}
+
+func TestLineRendererOSCSequenceTerminatedByST(t *testing.T) {
+	r := NewLineRenderer()
+	feedString(r, "before\x1b]0;window title\x1b\\afterward")
+	if got, want := r.Line(), "beforeafterward"; got != want {
+		t.Errorf("Line() = %q, want %q", got, want)
+	}
+}
+
+func TestLineRendererSGRStrippedByDefault(t *testing.T) {
+	keepColor = false
+	r := NewLineRenderer()
+	feedString(r, "\x1b[31mred\x1b[0m text")
+	if got, want := r.Line(), "red text"; got != want {
+		t.Errorf("Line() = %q, want %q", got, want)
+	}
+}
+
+func TestLineRendererSGRKeptWithFlag(t *testing.T) {
+	keepColor = true
+	defer func() { keepColor = false }()
+
+	r := NewLineRenderer()
+	feedString(r, "\x1b[31mred\x1b[0m")
+	if got, want := r.Line(), "\x1b[31mred\x1b[0m"; got != want {
+		t.Errorf("Line() = %q, want %q", got, want)
+	}
+}
+
+func TestLineRendererColoredOverwriteDoesNotAccumulatePrefixes(t *testing.T) {
+	keepColor = true
+	defer func() { keepColor = false }()
+
+	r := NewLineRenderer()
+	for i := 0; i < 2000; i++ {
+		feedString(r, "\x1b[31m1234\x1b[0m\r")
+	}
+
+	got := r.Line()
+	if got != "\x1b[0m\x1b[31m1234\x1b[0m" {
+		t.Errorf("Line() = %q (%d bytes), want a small fixed-size string, not one that grows with overwrite count", got, len(got))
+	}
+}
+
+func TestLineRendererReset(t *testing.T) {
+	r := NewLineRenderer()
+	feedString(r, "hello")
+	r.Reset()
+	if got := r.Line(); got != "" {
+		t.Errorf("Line() after Reset() = %q, want empty", got)
+	}
+	feedString(r, "world")
+	if got, want := r.Line(), "world"; got != want {
+		t.Errorf("Line() = %q, want %q", got, want)
+	}
+}
+
+func TestLineRendererUnknownEscapeDropsIntroducerOnly(t *testing.T) {
+	r := NewLineRenderer()
+	feedString(r, "hello\x1bworld")
+	if got, want := r.Line(), "helloworld"; got != want {
+		t.Errorf("Line() = %q, want %q", got, want)
+	}
+}